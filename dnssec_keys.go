@@ -0,0 +1,17 @@
+package dns
+
+import "crypto/ed25519"
+
+// publicKeyED25519 returns the Ed25519 public key from a KEY/DNSKEY record,
+// or nil if the key is malformed. It is the ED25519 sibling of
+// publicKeyDSA, publicKeyRSA and publicKeyCurve.
+func (k *KEY) publicKeyED25519() ed25519.PublicKey {
+	keybuf, err := fromBase64([]byte(k.PublicKey))
+	if err != nil {
+		return nil
+	}
+	if len(keybuf) != ed25519.PublicKeySize {
+		return nil
+	}
+	return ed25519.PublicKey(keybuf)
+}
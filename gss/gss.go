@@ -0,0 +1,247 @@
+// Package gss implements GSS-TSIG (RFC 3645) transaction authentication.
+//
+// GSS-TSIG layers the GSS-API security context negotiation of RFC 2930/3645
+// on top of TSIG: a client first negotiates a security context with a name
+// server using a TKEY exchange in mode 3 (GSS-API negotiation), and then
+// uses that context's GSS_GetMIC/GSS_VerifyMIC operations to compute and
+// check the TSIG MAC on every subsequent message, instead of the usual
+// HMAC-over-a-shared-secret. This is the mechanism Windows Active Directory
+// uses to authenticate secure dynamic updates.
+package gss
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// newSecurityContext and doExchangeTKEY are package vars, rather than
+// direct calls to newKerberosContext and exchangeTKEY, so tests can swap
+// in a fake securityContext and a canned TKEY exchange without a real
+// Kerberos environment or a listening name server.
+var (
+	newSecurityContext = newKerberosContext
+	doExchangeTKEY     = exchangeTKEY
+)
+
+// Algorithm is the TSIG algorithm name used to select GSS-TSIG.
+const Algorithm = "gss-tsig."
+
+var (
+	// ErrNoContext is returned by Generate/Verify when the client holds no
+	// negotiated security context, as after Close.
+	ErrNoContext = errors.New("gss: no security context established")
+	// ErrExpired is returned by Generate/Verify once the negotiated context
+	// has passed its TKEY expiration time.
+	ErrExpired = errors.New("gss: security context expired")
+)
+
+// securityContext abstracts the underlying GSSAPI mechanism so that
+// GSSClient can be built on Kerberos (via gokrb5; see gss_krb5.go). A
+// Windows-native SSPI backend can implement the same interface once one
+// exists; none is implemented today.
+type securityContext interface {
+	// initSecContext drives one leg of the GSS_Init_sec_context loop. On
+	// the first call inputToken is nil; on later calls it is the token
+	// the server returned in the previous TKEY exchange. It returns the
+	// token to send to the server next, and whether the context is now
+	// fully established.
+	initSecContext(inputToken []byte) (outputToken []byte, established bool, err error)
+	// getMIC computes a GSS_GetMIC token over message.
+	getMIC(message []byte) ([]byte, error)
+	// verifyMIC validates a GSS_VerifyMIC token over message.
+	verifyMIC(message, mic []byte) error
+	// delete releases any resources held by the context (GSS_Delete_sec_context).
+	delete() error
+}
+
+// GSSClient negotiates and caches a GSS-API security context for signing
+// and verifying GSS-TSIG messages. A GSSClient is safe for concurrent use.
+type GSSClient struct {
+	server string
+	spn    string
+
+	mu       sync.Mutex
+	contexts map[string]*clientContext
+}
+
+// clientContext is a negotiated security context for a single key name,
+// cached so repeated updates against the same server/principal don't pay
+// for a fresh TKEY exchange each time.
+type clientContext struct {
+	sec     securityContext
+	keyName string
+	expire  time.Time
+}
+
+// NewGSSClient negotiates a new GSS-API security context with server for
+// the given TKEY key name and service principal name spn, and returns a
+// GSSClient that can sign and verify messages under that context. server
+// is dialed using the standard DNS transport (TCP, per RFC 3645 §4.1.2) to
+// carry the TKEY mode 3 exchange.
+func NewGSSClient(server, keyname, spn string) (*GSSClient, error) {
+	c := &GSSClient{
+		server:   server,
+		spn:      spn,
+		contexts: make(map[string]*clientContext),
+	}
+	if err := c.negotiate(keyname); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// negotiate performs the TKEY mode 3 GSS-API negotiation for keyname and
+// stores the resulting context, keyed by server+principal so it can be
+// reused by later calls to Generate/Verify for the same name.
+//
+// RFC 2930 §2 and RFC 3645 §3 require the client's GSS-API token to travel
+// in the TKEY RDATA's Key field, and mutual authentication (the default
+// for Kerberos) typically takes more than one such round trip: the client
+// calls GSS_Init_sec_context, sends the resulting token in a TKEY query,
+// the server runs GSS_Accept_sec_context and returns its own token in the
+// TKEY reply, and the client feeds that back into GSS_Init_sec_context.
+// initSecContext reports established as soon as it has consumed that
+// reply and has nothing further to send, so negotiate checks established
+// right after calling it and stops before issuing another TKEY query —
+// otherwise the last round trip would carry an empty, meaningless token.
+func (c *GSSClient) negotiate(keyname string) error {
+	sec, err := newSecurityContext(c.spn)
+	if err != nil {
+		return err
+	}
+
+	var (
+		inputToken []byte
+		expire     uint32
+	)
+	for {
+		outputToken, established, err := sec.initSecContext(inputToken)
+		if err != nil {
+			sec.delete()
+			return err
+		}
+		if established {
+			break
+		}
+
+		tkey := &dns.TKEY{
+			Hdr: dns.RR_Header{
+				Name:   keyname,
+				Rrtype: dns.TypeTKEY,
+				Class:  dns.ClassANY,
+			},
+			Algorithm:  Algorithm,
+			Mode:       3, // GSS-API negotiation, RFC 2930 §2
+			Inception:  uint32(time.Now().Unix()),
+			Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+			Key:        hex.EncodeToString(outputToken),
+			KeySize:    uint16(len(outputToken)),
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion(keyname, dns.TypeTKEY)
+		m.Extra = append(m.Extra, tkey)
+		reply, err := doExchangeTKEY(c.server, m)
+		if err != nil {
+			sec.delete()
+			return err
+		}
+
+		replyTKEY, ok := replyTKEYRR(reply)
+		if !ok {
+			sec.delete()
+			return errors.New("gss: server reply carried no TKEY record")
+		}
+		expire = replyTKEY.Expiration
+
+		inputToken, err = hex.DecodeString(replyTKEY.Key)
+		if err != nil {
+			sec.delete()
+			return fmt.Errorf("gss: decoding server token: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	c.contexts[contextKey(c.server, c.spn)] = &clientContext{
+		sec:     sec,
+		keyName: keyname,
+		expire:  time.Unix(int64(expire), 0),
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// replyTKEYRR returns the TKEY record carried in m's answer/additional
+// sections, as returned by a server completing its side of exchangeTKEY.
+func replyTKEYRR(m *dns.Msg) (*dns.TKEY, bool) {
+	for _, rr := range m.Answer {
+		if tkey, ok := rr.(*dns.TKEY); ok {
+			return tkey, true
+		}
+	}
+	for _, rr := range m.Extra {
+		if tkey, ok := rr.(*dns.TKEY); ok {
+			return tkey, true
+		}
+	}
+	return nil, false
+}
+
+func contextKey(server, spn string) string { return server + "/" + spn }
+
+func (c *GSSClient) context() (*clientContext, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctx, ok := c.contexts[contextKey(c.server, c.spn)]
+	if !ok {
+		return nil, ErrNoContext
+	}
+	if time.Now().After(ctx.expire) {
+		return nil, ErrExpired
+	}
+	return ctx, nil
+}
+
+// Generate computes the GSS-TSIG MAC over msg, the wire format of a dns.Msg
+// with its TSIG RR stripped, as required by a dns.TsigProvider. It mirrors
+// the shape of the HMAC-based providers so GSSClient can be registered with
+// dns.Client.TsigProvider and dns.Server.TsigProvider.
+func (c *GSSClient) Generate(msg []byte, t *dns.TSIG) ([]byte, error) {
+	ctx, err := c.context()
+	if err != nil {
+		return nil, err
+	}
+	return ctx.sec.getMIC(msg)
+}
+
+// Verify validates the GSS-TSIG MAC carried in t against msg.
+func (c *GSSClient) Verify(msg []byte, t *dns.TSIG) error {
+	ctx, err := c.context()
+	if err != nil {
+		return err
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+	return ctx.sec.verifyMIC(msg, mac)
+}
+
+// Close releases the negotiated security context. Callers that are done
+// sending signed updates against server should call Close so the
+// underlying GSSAPI handle is freed promptly.
+func (c *GSSClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctx, ok := c.contexts[contextKey(c.server, c.spn)]
+	if !ok {
+		return nil
+	}
+	delete(c.contexts, contextKey(c.server, c.spn))
+	return ctx.sec.delete()
+}
@@ -0,0 +1,124 @@
+package gss
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestContextKey(t *testing.T) {
+	a := contextKey("ns1.example.org:53", "DNS/ns1.example.org")
+	b := contextKey("ns1.example.org:53", "DNS/ns2.example.org")
+	if a == b {
+		t.Fatal("contextKey collided for different principals")
+	}
+	if contextKey("ns1.example.org:53", "DNS/ns1.example.org") != a {
+		t.Fatal("contextKey is not deterministic")
+	}
+}
+
+// fakeSecurityContext is a securityContext stand-in for exercising
+// negotiate/Generate/Verify without a real Kerberos environment. It models
+// the same two-leg shape as krb5Context: the first initSecContext call
+// produces an outbound token and reports the context not yet established,
+// the second (fed the server's token) establishes it.
+type fakeSecurityContext struct {
+	key     []byte
+	leg     int
+	deleted bool
+}
+
+func (f *fakeSecurityContext) initSecContext(inputToken []byte) ([]byte, bool, error) {
+	f.leg++
+	if f.leg == 1 {
+		return []byte("client-token"), false, nil
+	}
+	if string(inputToken) != "server-token" {
+		return nil, false, errors.New("fakeSecurityContext: unexpected server token")
+	}
+	return nil, true, nil
+}
+
+func (f *fakeSecurityContext) getMIC(message []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+func (f *fakeSecurityContext) verifyMIC(message, mic []byte) error {
+	want, _ := f.getMIC(message)
+	if !hmac.Equal(want, mic) {
+		return errors.New("fakeSecurityContext: bad MIC")
+	}
+	return nil
+}
+
+func (f *fakeSecurityContext) delete() error {
+	f.deleted = true
+	return nil
+}
+
+func TestNegotiateGenerateVerify(t *testing.T) {
+	origNewSecurityContext, origDoExchangeTKEY := newSecurityContext, doExchangeTKEY
+	defer func() {
+		newSecurityContext, doExchangeTKEY = origNewSecurityContext, origDoExchangeTKEY
+	}()
+
+	sec := &fakeSecurityContext{key: []byte("fake-session-key")}
+	newSecurityContext = func(spn string) (securityContext, error) { return sec, nil }
+
+	exchanges := 0
+	doExchangeTKEY = func(server string, m *dns.Msg) (*dns.Msg, error) {
+		exchanges++
+		reply := new(dns.Msg)
+		reply.SetReply(m)
+		reply.Extra = append(reply.Extra, &dns.TKEY{
+			Hdr:        dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeTKEY, Class: dns.ClassANY},
+			Algorithm:  Algorithm,
+			Mode:       3,
+			Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+			Key:        hex.EncodeToString([]byte("server-token")),
+			KeySize:    uint16(len("server-token")),
+		})
+		return reply, nil
+	}
+
+	c, err := NewGSSClient("ns1.example.org:53", "client.sig-ns1.example.org.", "DNS/ns1.example.org")
+	if err != nil {
+		t.Fatalf("NewGSSClient: %v", err)
+	}
+
+	// Kerberos mutual auth is exactly one TKEY round trip: the client's
+	// AP-REQ out, the server's AP-REP back. A second, token-less exchange
+	// after that would be the bug this test guards against.
+	if exchanges != 1 {
+		t.Fatalf("expected exactly one TKEY exchange, got %d", exchanges)
+	}
+
+	msg := []byte("a signed dns message")
+	mac, err := c.Generate(msg, &dns.TSIG{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if err := c.Verify(msg, &dns.TSIG{MAC: hex.EncodeToString(mac)}); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := c.Verify([]byte("a different message"), &dns.TSIG{MAC: hex.EncodeToString(mac)}); err == nil {
+		t.Fatal("Verify succeeded against a tampered message")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !sec.deleted {
+		t.Fatal("Close did not delete the underlying security context")
+	}
+	if _, err := c.Generate(msg, &dns.TSIG{}); err != ErrNoContext {
+		t.Fatalf("Generate after Close: got %v, want ErrNoContext", err)
+	}
+}
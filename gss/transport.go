@@ -0,0 +1,25 @@
+package gss
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// exchangeTKEY sends the TKEY negotiation message m to server over TCP, as
+// required by RFC 3645 §4.1.2, and returns the server's reply so the caller
+// can pull the next leg's token (if any) out of its TKEY record. Each leg of
+// a multi-round-trip GSS-API negotiation is its own independent query/reply,
+// since TKEY carries no concept of a negotiation session beyond the owner
+// name and algorithm.
+func exchangeTKEY(server string, m *dns.Msg) (*dns.Msg, error) {
+	c := &dns.Client{Net: "tcp"}
+	in, _, err := c.Exchange(m, server)
+	if err != nil {
+		return nil, err
+	}
+	if in.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("gss: TKEY negotiation failed: %s", dns.RcodeToString[in.Rcode])
+	}
+	return in, nil
+}
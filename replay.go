@@ -0,0 +1,137 @@
+package dns
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// DefaultMaxClockSkew bounds how far in the past a SIG(0) signature's
+// inception may be, even if it hasn't expired yet, matching the behavior
+// BIND uses for signed dynamic updates (RFC 2931 §3.3 warns that the
+// incept/expire window alone leaves too wide a replay window).
+const DefaultMaxClockSkew = 300 * time.Second
+
+// ReplayCache lets VerifyWithCache reject a SIG(0) signature it has
+// already seen. Seen reports whether the signature identified by sigHash,
+// the SHA-256 of its raw signature bytes, has already been presented by
+// signerName with the given inception/expiration; an implementation
+// should record it if not, and isn't required to retain it past expire.
+type ReplayCache interface {
+	Seen(signerName string, incept, expire uint32, sigHash []byte) bool
+}
+
+// VerifyWithCache validates buf exactly as Verify does, then additionally
+// consults cache to reject a SIG it considers a replay (or, for the
+// default FIFOReplayCache, one whose inception is too old per its
+// MaxClockSkew). cache only ever records a signature once its
+// cryptographic signature has been confirmed valid, so a forged SIG can't
+// be used to poison the cache or evict real entries.
+func (rr *SIG) VerifyWithCache(k *KEY, buf []byte, cache ReplayCache) error {
+	p, err := rr.verifyPreimage(k, buf)
+	if err != nil {
+		return err
+	}
+
+	now := uint32(time.Now().Unix())
+	if now < p.incept || now > p.expire {
+		return ErrTime
+	}
+
+	if err := verifySignature(k, p.hashed, p.sig); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(p.sig)
+	if cache.Seen(p.signerName, p.incept, p.expire, sum[:]) {
+		return ErrSig
+	}
+	return nil
+}
+
+// fifoEntry is one cached signature, along with its SIG's expiration so
+// FIFOReplayCache can evict it without waiting for the cache to fill up.
+type fifoEntry struct {
+	key    string
+	expire uint32
+}
+
+// FIFOReplayCache is an in-memory ReplayCache keyed by the SHA-256 of the
+// signature bytes. When recording a new signature would take it past
+// capacity it first evicts the one it has held longest, in insertion
+// order — a hit in Seen doesn't refresh an entry's position, so this is a
+// FIFO cache, not a least-recently-used one. Every call to Seen that passes
+// the MaxClockSkew check also evicts any entry whose SIG has expired, and
+// an already-expired SIG is never recorded. It rejects any SIG whose
+// inception is older than MaxClockSkew, regardless of whether it has
+// expired. The zero value is not usable; use NewFIFOReplayCache.
+type FIFOReplayCache struct {
+	// MaxClockSkew is the furthest in the past a SIG's inception may be.
+	// It defaults to DefaultMaxClockSkew; set to 0 to disable the check
+	// entirely and rely on expire alone.
+	MaxClockSkew time.Duration
+
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]uint32 // sigHash -> expire
+	order   []fifoEntry
+}
+
+// NewFIFOReplayCache returns a FIFOReplayCache that retains at most
+// capacity signatures, with MaxClockSkew set to DefaultMaxClockSkew.
+func NewFIFOReplayCache(capacity int) *FIFOReplayCache {
+	return &FIFOReplayCache{
+		MaxClockSkew: DefaultMaxClockSkew,
+		capacity:     capacity,
+		entries:      make(map[string]uint32),
+	}
+}
+
+// Seen implements ReplayCache.
+func (c *FIFOReplayCache) Seen(signerName string, incept, expire uint32, sigHash []byte) bool {
+	now := uint32(time.Now().Unix())
+	if c.MaxClockSkew > 0 && now > incept && time.Duration(now-incept)*time.Second > c.MaxClockSkew {
+		return true
+	}
+
+	key := signerName + "/" + string(sigHash)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictExpired(now)
+
+	if _, ok := c.entries[key]; ok {
+		return true
+	}
+
+	// An already-expired SIG is accepted but not recorded, exactly as
+	// evictExpired would drop it.
+	if expire <= now {
+		return false
+	}
+
+	if c.capacity > 0 && len(c.order) >= c.capacity {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest.key)
+	}
+
+	c.entries[key] = expire
+	c.order = append(c.order, fifoEntry{key: key, expire: expire})
+	return false
+}
+
+// evictExpired drops every entry whose SIG has expired. c.mu must be held.
+func (c *FIFOReplayCache) evictExpired(now uint32) {
+	kept := c.order[:0]
+	for _, e := range c.order {
+		if e.expire <= now {
+			delete(c.entries, e.key)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	c.order = kept
+}
@@ -0,0 +1,65 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFIFOReplayCacheRejectsReplay(t *testing.T) {
+	c := NewFIFOReplayCache(10)
+	sigHash := []byte("deadbeef")
+	now := uint32(time.Now().Unix())
+
+	if c.Seen("example.org.", now-10, now+100, sigHash) {
+		t.Fatal("first presentation reported as already seen")
+	}
+	if !c.Seen("example.org.", now-10, now+100, sigHash) {
+		t.Fatal("replayed signature not detected")
+	}
+}
+
+func TestFIFOReplayCacheEvictsExpired(t *testing.T) {
+	c := NewFIFOReplayCache(10)
+	sigHash := []byte("deadbeef")
+	now := uint32(time.Now().Unix())
+
+	// Recent enough to pass the clock-skew check, but already expired, so
+	// Seen should both accept it (it's new) and immediately be willing to
+	// forget it.
+	incept := now - 10
+	expire := now - 5
+	if c.Seen("example.org.", incept, expire, sigHash) {
+		t.Fatal("first presentation reported as already seen")
+	}
+	if len(c.entries) != 0 {
+		t.Fatalf("expired entry was not evicted: %d entries remain", len(c.entries))
+	}
+}
+
+func TestFIFOReplayCacheEvictsOldestOverCapacity(t *testing.T) {
+	c := NewFIFOReplayCache(2)
+	now := uint32(time.Now().Unix())
+	incept := now - 10
+	future := now + 1<<20
+
+	c.Seen("a.example.org.", incept, future, []byte("a"))
+	c.Seen("b.example.org.", incept, future, []byte("b"))
+	c.Seen("c.example.org.", incept, future, []byte("c"))
+
+	if len(c.entries) != 2 {
+		t.Fatalf("expected capacity to be enforced, got %d entries", len(c.entries))
+	}
+	if c.Seen("a.example.org.", incept, future, []byte("a")) {
+		t.Fatal("oldest entry should have been evicted, not remembered as a replay")
+	}
+}
+
+func TestFIFOReplayCacheMaxClockSkew(t *testing.T) {
+	c := NewFIFOReplayCache(10)
+	c.MaxClockSkew = DefaultMaxClockSkew
+
+	tooOld := uint32(1) // 1970-ish, far outside any reasonable skew
+	if !c.Seen("example.org.", tooOld, tooOld+1<<20, []byte("x")) {
+		t.Fatal("signature with inception far in the past should be rejected as too old")
+	}
+}
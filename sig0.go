@@ -14,7 +14,7 @@ import (
 	"crypto"
 	"crypto/dsa"
 	"crypto/ecdsa"
-	"crypto/rand"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"fmt"
 	"math/big"
@@ -25,105 +25,30 @@ import (
 // Sign signs a dns.Msg. It fills the signature with the appropriate data.
 // The SIG record should have the SignerName, KeyTag, Algorithm, Inception
 // and Expiration set.
+//
+// Sign is a thin wrapper around Signer: it appends the SIG RR returned by
+// Finalize to the message packed by WriteMsg and increments ARCOUNT. Code
+// that only needs the SIG RR bytes, to append to its own copy of the
+// message, can use NewSigner directly.
 func (rr *SIG) Sign(k PrivateKey, m *Msg) ([]byte, error) {
-	if k == nil {
-		return nil, ErrPrivKey
-	}
-	if rr.KeyTag == 0 || len(rr.SignerName) == 0 || rr.Algorithm == 0 {
-		return nil, ErrKey
-	}
-	rr.Header().Rrtype = TypeSIG
-	rr.Header().Class = ClassANY
-	rr.Header().Ttl = 0
-	rr.Header().Name = "."
-	rr.OrigTtl = 0
-	rr.TypeCovered = 0
-	rr.Labels = 0
-
-	buflen := m.Len() + rr.len()
-	switch k := k.(type) {
-	case *rsa.PrivateKey:
-		buflen += len(k.N.Bytes())
-	case *dsa.PrivateKey:
-		buflen += 40
-	case *ecdsa.PrivateKey:
-		buflen += 96
-	default:
-		return nil, ErrPrivKey
-	}
-	buf := make([]byte, m.Len()+rr.len()+buflen)
-	mbuf, err := m.PackBuffer(buf)
+	s, err := NewSigner(rr, k)
 	if err != nil {
 		return nil, err
 	}
-	if &buf[0] != &mbuf[0] {
-		return nil, ErrBuf
+	if err := s.WriteMsg(m); err != nil {
+		return nil, err
 	}
-	off, err := PackRR(rr, buf, len(mbuf), nil, false)
+	sigrr, err := s.Finalize()
 	if err != nil {
 		return nil, err
 	}
-	buf = buf[:off:cap(buf)]
-	var hash crypto.Hash
-	switch rr.Algorithm {
-	case DSA, RSASHA1:
-		hash = crypto.SHA1
-	case RSASHA256, ECDSAP256SHA256:
-		hash = crypto.SHA256
-	case ECDSAP384SHA384:
-		hash = crypto.SHA384
-	case RSASHA512:
-		hash = crypto.SHA512
-	default:
-		return nil, ErrAlg
-	}
-	hasher := hash.New()
-	// Write SIG rdata
-	hasher.Write(buf[len(mbuf)+1+2+2+4+2:])
-	// Write message
-	hasher.Write(buf[:len(mbuf)])
-	hashed := hasher.Sum(nil)
 
-	var sig []byte
-	switch p := k.(type) {
-	case *dsa.PrivateKey:
-		t := byte((len(p.PublicKey.Y.Bytes()) - 64) / 8)
-		r1, s1, err := dsa.Sign(rand.Reader, p, hashed)
-		if err != nil {
-			return nil, err
-		}
-		sig = make([]byte, 0, 1+len(r1.Bytes())+len(s1.Bytes()))
-		sig = append(sig, t)
-		sig = append(sig, r1.Bytes()...)
-		sig = append(sig, s1.Bytes()...)
-	case *rsa.PrivateKey:
-		sig, err = rsa.SignPKCS1v15(rand.Reader, p, hash, hashed)
-		if err != nil {
-			return nil, err
-		}
-	case *ecdsa.PrivateKey:
-		r1, s1, err := ecdsa.Sign(rand.Reader, p, hashed)
-		if err != nil {
-			return nil, err
-		}
-		sig = r1.Bytes()
-		sig = append(sig, s1.Bytes()...)
-	default:
-		return nil, ErrAlg
-	}
-	rr.Signature = unpackBase64(sig)
-	buf = append(buf, sig...)
+	buf := append(s.msg, sigrr...)
 	if len(buf) > int(^uint16(0)) {
 		return nil, ErrBuf
 	}
-	// Adjust sig data length
-	rdoff := len(mbuf) + 1 + 2 + 2 + 4
-	rdlen, _ := unpackUint16(buf, rdoff)
-	rdlen += uint16(len(sig))
-	buf[rdoff], buf[rdoff+1] = packUint16(rdlen)
-	// Adjust additional count
 	adc, _ := unpackUint16(buf, 10)
-	adc += 1
+	adc++
 	buf[10], buf[11] = packUint16(adc)
 	return buf, nil
 }
@@ -131,11 +56,38 @@ func (rr *SIG) Sign(k PrivateKey, m *Msg) ([]byte, error) {
 // Verify validates the message buf using the key k.
 // It's assumed that buf is a valid message from which rr was unpacked.
 func (rr *SIG) Verify(k *KEY, buf []byte) error {
+	p, err := rr.verifyPreimage(k, buf)
+	if err != nil {
+		return err
+	}
+	now := uint32(time.Now().Unix())
+	if now < p.incept || now > p.expire {
+		return ErrTime
+	}
+	return verifySignature(k, p.hashed, p.sig)
+}
+
+// sigPreimage holds everything Verify and VerifyWithCache need out of a
+// parsed SIG(0) message besides the time/replay policy each applies
+// differently.
+type sigPreimage struct {
+	incept, expire uint32
+	signerName     string
+	hashed         []byte
+	sig            []byte
+}
+
+// verifyPreimage walks buf to find the SIG RR rr was unpacked from, checks
+// its signer name against k, and returns the (possibly hashed) preimage
+// and raw signature bytes ready for verifySignature, along with the SIG's
+// inception and expiration. It does not itself enforce the validity
+// window, so callers can apply their own time/replay policy first.
+func (rr *SIG) verifyPreimage(k *KEY, buf []byte) (*sigPreimage, error) {
 	if k == nil {
-		return ErrKey
+		return nil, ErrKey
 	}
 	if rr.KeyTag == 0 || len(rr.SignerName) == 0 || rr.Algorithm == 0 {
-		return ErrKey
+		return nil, ErrKey
 	}
 
 	var hash crypto.Hash
@@ -148,10 +100,14 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 		hash = crypto.SHA384
 	case RSASHA512:
 		hash = crypto.SHA512
+	case ED25519:
+		// No pre-hashing step; Ed25519 verifies the message directly.
+		// ED448 is deliberately not accepted here: the standard library
+		// has no Ed448 implementation, so there's nothing for
+		// verifySignature to call.
 	default:
-		return ErrAlg
+		return nil, ErrAlg
 	}
-	hasher := hash.New()
 
 	buflen := len(buf)
 	qdc, _ := unpackUint16(buf, 4)
@@ -163,7 +119,7 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 		// decode a name
 		_, offset, err = UnpackDomainName(buf, offset)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// skip past Type and Class
 		offset += 2 + 2
@@ -172,7 +128,7 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 		// decode a name
 		_, offset, err = UnpackDomainName(buf, offset)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// skip past Type, Class and TTL
 		offset += 2 + 2 + 4
@@ -185,7 +141,7 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 	// Owner name SHOULD be root
 	_, offset, err = UnpackDomainName(buf, offset)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Skip Type, Class, TTL, RDLen
 	offset += 2 + 2 + 4 + 2
@@ -199,32 +155,43 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 	var expire, incept uint32
 	expire, offset = unpackUint32(buf, offset)
 	incept, offset = unpackUint32(buf, offset)
-	now := uint32(time.Now().Unix())
-	if now < incept || now > expire {
-		return ErrTime
-	}
 	offset += 2 // skip key tag
 	var signername string
 	signername, offset, err = UnpackDomainName(buf, offset)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// If key has come from the DNS name compression might
 	// have mangled the case of the name
 	if strings.ToLower(signername) != strings.ToLower(k.Header().Name) {
-		return fmt.Errorf("Signer name doesn't match key name")
+		return nil, fmt.Errorf("Signer name doesn't match key name")
 	}
 	sigend := offset
-	hasher.Write(buf[sigstart:sigend])
-	hasher.Write(buf[:10])
-	hasher.Write([]byte{
-		byte((adc - 1) << 8),
-		byte(adc - 1),
-	})
-	hasher.Write(buf[12:bodyend])
+	signdata := append(append([]byte(nil), buf[sigstart:sigend]...), buf[:10]...)
+	signdata = append(signdata, byte((adc-1)<<8), byte(adc-1))
+	signdata = append(signdata, buf[12:bodyend]...)
+
+	var hashed []byte
+	if hash != 0 {
+		hasher := hash.New()
+		hasher.Write(signdata)
+		hashed = hasher.Sum(nil)
+	} else {
+		hashed = signdata
+	}
 
-	hashed := hasher.Sum(nil)
-	sig := buf[sigend:]
+	return &sigPreimage{
+		incept:     incept,
+		expire:     expire,
+		signerName: signername,
+		hashed:     hashed,
+		sig:        buf[sigend:],
+	}, nil
+}
+
+// verifySignature checks hashed (the SIG(0) preimage, hashed unless k's
+// algorithm signs it directly) against sig using k's public key.
+func verifySignature(k *KEY, hashed, sig []byte) error {
 	switch k.Algorithm {
 	case DSA:
 		pk := k.publicKeyDSA()
@@ -242,6 +209,15 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 	case RSASHA1, RSASHA256, RSASHA512:
 		pk := k.publicKeyRSA()
 		if pk != nil {
+			var hash crypto.Hash
+			switch k.Algorithm {
+			case RSASHA1:
+				hash = crypto.SHA1
+			case RSASHA256:
+				hash = crypto.SHA256
+			case RSASHA512:
+				hash = crypto.SHA512
+			}
 			return rsa.VerifyPKCS1v15(pk, hash, hashed, sig)
 		}
 	case ECDSAP256SHA256, ECDSAP384SHA384:
@@ -256,6 +232,14 @@ func (rr *SIG) Verify(k *KEY, buf []byte) error {
 			}
 			return ErrSig
 		}
+	case ED25519:
+		pk := k.publicKeyED25519()
+		if pk != nil {
+			if ed25519.Verify(pk, hashed, sig) {
+				return nil
+			}
+			return ErrSig
+		}
 	}
 	return ErrKeyAlg
-}
\ No newline at end of file
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+)
+
+func TestSIG0Ed25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	keyrr := &KEY{
+		Hdr: RR_Header{Name: "example.org.", Rrtype: TypeKEY, Class: ClassINET},
+		Algorithm: ED25519,
+		Protocol:  3,
+		PublicKey: toBase64(pub),
+	}
+
+	now := uint32(time.Now().Unix())
+	sigrr := &SIG{
+		Hdr:        RR_Header{Name: ".", Rrtype: TypeSIG, Class: ClassANY},
+		Algorithm:  ED25519,
+		SignerName: "example.org.",
+		KeyTag:     keyrr.KeyTag(),
+		Inception:  now - 300,
+		Expiration: now + 300,
+	}
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+
+	buf, err := sigrr.Sign(priv, m)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	signed := new(Msg)
+	if err := signed.Unpack(buf); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	unpackedSig, ok := signed.Extra[len(signed.Extra)-1].(*SIG)
+	if !ok {
+		t.Fatalf("expected last additional record to be a SIG, got %T", signed.Extra[len(signed.Extra)-1])
+	}
+
+	if err := unpackedSig.Verify(keyrr, buf); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// A bit-flipped signature must not verify.
+	tampered := append([]byte(nil), buf...)
+	tampered[len(tampered)-1] ^= 0xff
+	if err := unpackedSig.Verify(keyrr, tampered); err == nil {
+		t.Fatal("Verify succeeded on a tampered signature")
+	}
+}
+
+// ED448 is recognized as an algorithm number but isn't implemented (the
+// standard library has no Ed448 support); NewSigner must reject it rather
+// than silently falling back to some other algorithm.
+func TestSIG0Ed448Unsupported(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	sigrr := &SIG{
+		Hdr:        RR_Header{Name: ".", Rrtype: TypeSIG, Class: ClassANY},
+		Algorithm:  ED448,
+		SignerName: "example.org.",
+		KeyTag:     1,
+		Inception:  1,
+		Expiration: 2,
+	}
+
+	if _, err := NewSigner(sigrr, priv); err != ErrAlg {
+		t.Fatalf("NewSigner with ED448: got %v, want ErrAlg", err)
+	}
+}
@@ -0,0 +1,206 @@
+package dns
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"hash"
+	"io"
+)
+
+// Signer computes a SIG(0) signature over a message in two steps: WriteMsg
+// packs the message and feeds it to the hash, and Finalize returns only the
+// signed SIG RR's wire bytes rather than a full signed message. SIG.Sign is
+// built on it. WriteMsg packs the whole message into one buffer before
+// hashing it; it does not hash while packing. For ED25519, which has no
+// pre-hashing step, the Signer also keeps a second copy of the message (as
+// part of the preimage) until Finalize.
+type Signer struct {
+	rr   *SIG
+	k    PrivateKey
+	hash crypto.Hash // zero for ED25519, which signs the message directly
+	h    hash.Hash
+	raw  []byte // buffered preimage, used in place of h for ED25519
+
+	msg   []byte // the packed message, kept so Sign doesn't have to pack it twice
+	wrote bool
+}
+
+// NewSigner returns a Signer that will produce a SIG(0) signature for rr
+// using the private key k. rr must already have SignerName, KeyTag and
+// Algorithm set, exactly as required by SIG.Sign.
+func NewSigner(rr *SIG, k PrivateKey) (*Signer, error) {
+	if k == nil {
+		return nil, ErrPrivKey
+	}
+	if rr.KeyTag == 0 || len(rr.SignerName) == 0 || rr.Algorithm == 0 {
+		return nil, ErrKey
+	}
+	switch k.(type) {
+	case *rsa.PrivateKey, *dsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, ErrPrivKey
+	}
+
+	var ch crypto.Hash
+	switch rr.Algorithm {
+	case DSA, RSASHA1:
+		ch = crypto.SHA1
+	case RSASHA256, ECDSAP256SHA256:
+		ch = crypto.SHA256
+	case ECDSAP384SHA384:
+		ch = crypto.SHA384
+	case RSASHA512:
+		ch = crypto.SHA512
+	case ED25519:
+		// No pre-hashing step.
+		// ED448 is deliberately not accepted here: the standard library
+		// has no Ed448 implementation for Finalize to call.
+	default:
+		return nil, ErrAlg
+	}
+
+	s := &Signer{rr: rr, k: k, hash: ch}
+	if ch != 0 {
+		s.h = ch.New()
+	}
+	return s, nil
+}
+
+// WriteMsg packs m into one buffer, then writes the SIG rdata followed by
+// that buffer into the Signer's hash (for ED25519, into an in-memory
+// preimage). It must be called exactly once, before Finalize.
+func (s *Signer) WriteMsg(m *Msg) error {
+	if s.wrote {
+		return ErrBuf
+	}
+
+	s.rr.Header().Rrtype = TypeSIG
+	s.rr.Header().Class = ClassANY
+	s.rr.Header().Ttl = 0
+	s.rr.Header().Name = "."
+	s.rr.OrigTtl = 0
+	s.rr.TypeCovered = 0
+	s.rr.Labels = 0
+
+	mbuf := make([]byte, m.Len())
+	mbuf, err := m.PackBuffer(mbuf)
+	if err != nil {
+		return err
+	}
+
+	rdata := make([]byte, s.rr.len())
+	if _, err := PackRR(s.rr, rdata, 0, nil, false); err != nil {
+		return err
+	}
+	// rdata is [owner(1) type(2) class(2) ttl(4) rdlen(2) <rdata...>];
+	// only the rdata itself is covered by the signature.
+	sigrdata := rdata[1+2+2+4+2:]
+
+	if s.h != nil {
+		s.h.Write(sigrdata)
+		s.h.Write(mbuf)
+	} else {
+		s.raw = append(append([]byte(nil), sigrdata...), mbuf...)
+	}
+	s.msg = mbuf
+	s.wrote = true
+	return nil
+}
+
+// Finalize signs the accumulated message and returns the wire format of
+// the completed SIG RR: owner, type, class, TTL, RDLENGTH, rdata and
+// signature, in the order SIG.Sign would append them to a packed message.
+// The caller is responsible for appending these bytes to its own message
+// buffer and incrementing the header's ARCOUNT.
+func (s *Signer) Finalize() ([]byte, error) {
+	if !s.wrote {
+		return nil, ErrBuf
+	}
+
+	var hashed []byte
+	if s.h != nil {
+		hashed = s.h.Sum(nil)
+	} else {
+		hashed = s.raw
+	}
+
+	var sig []byte
+	var err error
+	switch p := s.k.(type) {
+	case *dsa.PrivateKey:
+		t := byte((len(p.PublicKey.Y.Bytes()) - 64) / 8)
+		r1, s1, err := dsa.Sign(rand.Reader, p, hashed)
+		if err != nil {
+			return nil, err
+		}
+		sig = make([]byte, 0, 1+len(r1.Bytes())+len(s1.Bytes()))
+		sig = append(sig, t)
+		sig = append(sig, r1.Bytes()...)
+		sig = append(sig, s1.Bytes()...)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, p, s.hash, hashed)
+		if err != nil {
+			return nil, err
+		}
+	case *ecdsa.PrivateKey:
+		r1, s1, err := ecdsa.Sign(rand.Reader, p, hashed)
+		if err != nil {
+			return nil, err
+		}
+		sig = r1.Bytes()
+		sig = append(sig, s1.Bytes()...)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(p, hashed)
+	default:
+		return nil, ErrAlg
+	}
+	s.rr.Signature = unpackBase64(sig)
+
+	rdata := make([]byte, s.rr.len())
+	off, err := PackRR(s.rr, rdata, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return rdata[:off], nil
+}
+
+// Verifier validates a SIG(0) signature over a single message read off a
+// TCP DNS stream, length-prefixed per RFC 1035 §4.2.2, so a server handling
+// several messages on one connection doesn't have to frame, size and
+// assemble each one into its own buffer before calling Verify. Domain name
+// decompression within a message can reference any earlier offset in that
+// message, so Verify still has to hold one whole message in memory to
+// parse it, same as SIG.Verify; calling it repeatedly on the same r
+// verifies each successive message on the stream in turn.
+type Verifier struct {
+	rr *SIG
+	k  *KEY
+	r  io.Reader
+}
+
+// NewVerifier returns a Verifier that checks rr, unpacked from messages
+// read from r, against the key k.
+func NewVerifier(rr *SIG, k *KEY, r io.Reader) *Verifier {
+	return &Verifier{rr: rr, k: k, r: r}
+}
+
+// Verify reads one length-prefixed message from the Verifier's reader and
+// validates its SIG(0) signature, as SIG.Verify would for an in-memory
+// buffer.
+func (v *Verifier) Verify() error {
+	var lenbuf [2]byte
+	if _, err := io.ReadFull(v.r, lenbuf[:]); err != nil {
+		return err
+	}
+	length := int(lenbuf[0])<<8 | int(lenbuf[1])
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(v.r, buf); err != nil {
+		return err
+	}
+	return v.rr.Verify(v.k, buf)
+}
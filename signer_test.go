@@ -0,0 +1,120 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+	"time"
+)
+
+func TestSignerFinalizeMatchesSign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	keyrr := &KEY{
+		Hdr:       RR_Header{Name: "example.org.", Rrtype: TypeKEY, Class: ClassINET},
+		Algorithm: ED25519,
+		Protocol:  3,
+		PublicKey: toBase64(pub),
+	}
+
+	now := uint32(time.Now().Unix())
+	newSIG := func() *SIG {
+		return &SIG{
+			Hdr:        RR_Header{Name: ".", Rrtype: TypeSIG, Class: ClassANY},
+			Algorithm:  ED25519,
+			SignerName: "example.org.",
+			KeyTag:     keyrr.KeyTag(),
+			Inception:  now - 300,
+			Expiration: now + 300,
+		}
+	}
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+
+	buf, err := newSIG().Sign(priv, m)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	s, err := NewSigner(newSIG(), priv)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	if err := s.WriteMsg(m); err != nil {
+		t.Fatalf("WriteMsg: %v", err)
+	}
+	sigrr, err := s.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+	streamed := append(append([]byte(nil), s.msg...), sigrr...)
+	adc, _ := unpackUint16(streamed, 10)
+	adc++
+	streamed[10], streamed[11] = packUint16(adc)
+
+	if !bytes.Equal(buf, streamed) {
+		t.Fatalf("Signer.Finalize produced a different wire format than Sign:\n sign: %x\nsigner: %x", buf, streamed)
+	}
+}
+
+func TestVerifierReadsLengthPrefixedStream(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	keyrr := &KEY{
+		Hdr:       RR_Header{Name: "example.org.", Rrtype: TypeKEY, Class: ClassINET},
+		Algorithm: ED25519,
+		Protocol:  3,
+		PublicKey: toBase64(pub),
+	}
+
+	now := uint32(time.Now().Unix())
+	sigrr := &SIG{
+		Hdr:        RR_Header{Name: ".", Rrtype: TypeSIG, Class: ClassANY},
+		Algorithm:  ED25519,
+		SignerName: "example.org.",
+		KeyTag:     keyrr.KeyTag(),
+		Inception:  now - 300,
+		Expiration: now + 300,
+	}
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	buf, err := sigrr.Sign(priv, m)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	var stream bytes.Buffer
+	stream.WriteByte(byte(len(buf) >> 8))
+	stream.WriteByte(byte(len(buf)))
+	stream.Write(buf)
+	// A second message on the same stream, to make sure Verify only
+	// consumes its own length-prefixed frame.
+	stream.WriteByte(byte(len(buf) >> 8))
+	stream.WriteByte(byte(len(buf)))
+	stream.Write(buf)
+
+	signed := new(Msg)
+	if err := signed.Unpack(buf); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	unpackedSig := signed.Extra[len(signed.Extra)-1].(*SIG)
+
+	v := NewVerifier(unpackedSig, keyrr, &stream)
+	if err := v.Verify(); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+	if err := v.Verify(); err != nil {
+		t.Fatalf("second Verify: %v", err)
+	}
+	if stream.Len() != 0 {
+		t.Fatalf("Verify left %d unread bytes on the stream", stream.Len())
+	}
+}